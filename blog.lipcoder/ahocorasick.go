@@ -0,0 +1,88 @@
+package main
+
+// 一个小号的 Aho-Corasick：建一棵 trie，用 BFS 补上 fail 指针，然后一遍扫描就能
+// 把输入里命中的所有敏感词都找出来，O(n + matches)，词表几千条也不怕。
+type acNode struct {
+	children map[rune]*acNode
+	fail     *acNode
+	output   []string
+}
+
+func newACNode() *acNode {
+	return &acNode{children: make(map[rune]*acNode)}
+}
+
+// ACMatcher 是构建好的自动机，构建完成后可以并发只读地调用 Scan。
+type ACMatcher struct {
+	root *acNode
+}
+
+// buildAC 用给定的模式串（已经做过大小写/normalize 处理）建自动机。
+func buildAC(patterns []string) *ACMatcher {
+	root := newACNode()
+	for _, p := range patterns {
+		if p == "" {
+			continue
+		}
+		node := root
+		for _, r := range p {
+			child, ok := node.children[r]
+			if !ok {
+				child = newACNode()
+				node.children[r] = child
+			}
+			node = child
+		}
+		node.output = append(node.output, p)
+	}
+
+	// BFS 建 fail 指针：根的直接子节点 fail 指回根，之后每层按 BFS 顺序继承。
+	queue := make([]*acNode, 0, len(root.children))
+	for _, child := range root.children {
+		child.fail = root
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for r, child := range node.children {
+			queue = append(queue, child)
+			fail := node.fail
+			for fail != nil {
+				if next, ok := fail.children[r]; ok {
+					child.fail = next
+					break
+				}
+				fail = fail.fail
+			}
+			if fail == nil {
+				child.fail = root
+			}
+			child.output = append(child.output, child.fail.output...)
+		}
+	}
+
+	return &ACMatcher{root: root}
+}
+
+// Scan 返回文本里命中的第一个模式串；没命中返回 ""、false。
+func (m *ACMatcher) Scan(text string) (string, bool) {
+	node := m.root
+	for _, r := range text {
+		for node != m.root {
+			if _, ok := node.children[r]; ok {
+				break
+			}
+			node = node.fail
+		}
+		if next, ok := node.children[r]; ok {
+			node = next
+		} else {
+			node = m.root
+		}
+		if len(node.output) > 0 {
+			return node.output[0], true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,48 @@
+package main
+
+import "testing"
+
+func TestACMatcherScanFindsHit(t *testing.T) {
+	m := buildAC([]string{"foo", "bar", "baz"})
+
+	word, ok := m.Scan("some text with a bar in it")
+	if !ok || word != "bar" {
+		t.Fatalf("Scan() = %q, %v, want \"bar\", true", word, ok)
+	}
+}
+
+func TestACMatcherScanNoHit(t *testing.T) {
+	m := buildAC([]string{"foo", "bar"})
+
+	if word, ok := m.Scan("completely unrelated text"); ok {
+		t.Fatalf("Scan() = %q, true, want no match", word)
+	}
+}
+
+func TestACMatcherScanOverlappingPatterns(t *testing.T) {
+	// "she" 是 "he" 的父串一部分重叠，fail 指针要能正确地在扫完 "she" 之后
+	// 依然认出 "he"。
+	m := buildAC([]string{"he", "she", "his", "hers"})
+
+	word, ok := m.Scan("she sells seashells")
+	if !ok {
+		t.Fatalf("Scan() found no match in %q, want a hit", "she sells seashells")
+	}
+	if word != "she" && word != "he" {
+		t.Fatalf("Scan() = %q, want \"she\" or \"he\"", word)
+	}
+}
+
+func TestACMatcherEmptyPatternsNeverMatch(t *testing.T) {
+	m := buildAC(nil)
+	if _, ok := m.Scan("anything at all"); ok {
+		t.Fatal("Scan() on empty matcher matched, want no match")
+	}
+}
+
+func TestACMatcherIgnoresEmptyPattern(t *testing.T) {
+	m := buildAC([]string{"", "spam"})
+	if word, ok := m.Scan("buy spam now"); !ok || word != "spam" {
+		t.Fatalf("Scan() = %q, %v, want \"spam\", true", word, ok)
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/bmaupin/go-epub"
+	"github.com/go-pdf/fpdf"
+)
+
+// 文章导出：/post/{slug}.pdf、.epub、.html（单文件自包含）。优先用 $PATH 上的
+// wkhtmltopdf / ebook-convert（转出来效果更好），没装的机器退回纯 Go 实现。
+const exportCacheDir = "./data/export-cache"
+
+// Converter 是可插拔的文档转换后端。
+type Converter interface {
+	ConvertPDF(post *Post) ([]byte, error)
+	ConvertEPUB(post *Post) ([]byte, error)
+}
+
+var converter Converter
+
+// detectConverter 在启动时探测一次 $PATH，有 wkhtmltopdf/ebook-convert 就用它们，
+// 否则退回纯 Go 实现（fpdf 生成 PDF，go-epub 生成 EPUB）。
+func detectConverter() {
+	_, errWkhtmltopdf := exec.LookPath("wkhtmltopdf")
+	_, errEbookConvert := exec.LookPath("ebook-convert")
+	converter = &shellConverter{
+		hasWkhtmltopdf:  errWkhtmltopdf == nil,
+		hasEbookConvert: errEbookConvert == nil,
+		fallback:        &pureGoConverter{},
+	}
+}
+
+var exportTpl = template.Must(template.New("export").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>{{.Title}}</title>
+<style>
+body{font-family:serif;max-width:760px;margin:2em auto;line-height:1.6;padding:0 1em}
+h1,h2,h3{font-family:sans-serif}
+</style></head>
+<body>
+<h1>{{.Title}}</h1>
+{{.HTML}}
+</body></html>`))
+
+// renderStandaloneHTML 把 Post.HTML 包进一个带内嵌 CSS 的打印友好模板里。
+func renderStandaloneHTML(post *Post) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := exportTpl.Execute(&buf, post); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type shellConverter struct {
+	hasWkhtmltopdf  bool
+	hasEbookConvert bool
+	fallback        Converter
+}
+
+func (c *shellConverter) ConvertPDF(post *Post) ([]byte, error) {
+	if !c.hasWkhtmltopdf {
+		return c.fallback.ConvertPDF(post)
+	}
+	htmlBytes, err := renderStandaloneHTML(post)
+	if err != nil {
+		return nil, err
+	}
+	return runShellConvert("wkhtmltopdf", htmlBytes, ".html", ".pdf")
+}
+
+func (c *shellConverter) ConvertEPUB(post *Post) ([]byte, error) {
+	if !c.hasEbookConvert {
+		return c.fallback.ConvertEPUB(post)
+	}
+	htmlBytes, err := renderStandaloneHTML(post)
+	if err != nil {
+		return nil, err
+	}
+	return runShellConvert("ebook-convert", htmlBytes, ".html", ".epub")
+}
+
+// runShellConvert 把 html 写到临时文件，跑外部工具转换，再把结果读回来。
+func runShellConvert(tool string, input []byte, inExt, outExt string) ([]byte, error) {
+	inFile, err := os.CreateTemp("", "export-in-*"+inExt)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(inFile.Name())
+	if _, err := inFile.Write(input); err != nil {
+		inFile.Close()
+		return nil, err
+	}
+	inFile.Close()
+
+	outPath := strings.TrimSuffix(inFile.Name(), inExt) + outExt
+	defer os.Remove(outPath)
+
+	cmd := exec.Command(tool, inFile.Name(), outPath)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("%s failed: %v (%s)", tool, err, out)
+	}
+	return os.ReadFile(outPath)
+}
+
+// pureGoConverter 在没有 wkhtmltopdf/ebook-convert 的机器上兜底：PDF 用 fpdf，
+// EPUB 用 go-epub。排版比不上专门的工具，但能用。
+type pureGoConverter struct{}
+
+func (pureGoConverter) ConvertPDF(post *Post) ([]byte, error) {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+	pdf.SetFont("Arial", "B", 16)
+	pdf.MultiCell(0, 10, post.Title, "", "L", false)
+	pdf.SetFont("Arial", "", 11)
+	pdf.MultiCell(0, 6, post.Raw, "", "L", false)
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (pureGoConverter) ConvertEPUB(post *Post) ([]byte, error) {
+	e := epub.NewEpub(post.Title)
+	e.SetTitle(post.Title)
+	if _, err := e.AddSection(string(post.HTML), post.Title, "", ""); err != nil {
+		return nil, err
+	}
+
+	tmpPath := filepath.Join(os.TempDir(), "export-"+post.Slug+".epub")
+	defer os.Remove(tmpPath)
+	if err := e.Write(tmpPath); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(tmpPath)
+}
+
+// handlePostExport 处理 /post/{slug}.pdf|.epub|.html，按 post 的 mtime 做缓存，
+// 重复下载不用每次都重新转换。
+func handlePostExport(w http.ResponseWriter, r *http.Request, post *Post, format string) {
+	data, contentType, err := cachedExport(post, format)
+	if err != nil {
+		http.Error(w, "导出失败: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(data)
+}
+
+func cachedExport(post *Post, format string) (data []byte, contentType string, err error) {
+	if err := os.MkdirAll(exportCacheDir, 0o755); err != nil {
+		return nil, "", err
+	}
+	cacheFile := filepath.Join(exportCacheDir, fmt.Sprintf("%s-%d.%s", post.Slug, post.Date.Unix(), format))
+
+	if cached, err := os.ReadFile(cacheFile); err == nil {
+		return cached, contentTypeFor(format), nil
+	}
+
+	switch format {
+	case "html":
+		data, err = renderStandaloneHTML(post)
+	case "pdf":
+		data, err = converter.ConvertPDF(post)
+	case "epub":
+		data, err = converter.ConvertEPUB(post)
+	default:
+		err = fmt.Errorf("unsupported format %q", format)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	_ = os.WriteFile(cacheFile, data, 0o644)
+	return data, contentTypeFor(format), nil
+}
+
+func contentTypeFor(format string) string {
+	switch format {
+	case "pdf":
+		return "application/pdf"
+	case "epub":
+		return "application/epub+zip"
+	default:
+		return "text/html; charset=utf-8"
+	}
+}
+
+// handleIndexBundle 支持 /?bundle=zip，把所有文章的单文件 HTML 打包成一个 zip。
+func handleIndexBundle(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, post := range getAllPosts() {
+		htmlBytes, err := renderStandaloneHTML(post)
+		if err != nil {
+			continue
+		}
+		f, err := zw.Create(post.Slug + ".html")
+		if err != nil {
+			continue
+		}
+		_, _ = f.Write(htmlBytes)
+	}
+	if err := zw.Close(); err != nil {
+		http.Error(w, "打包失败", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="posts.zip"`)
+	w.Write(buf.Bytes())
+}
+
+// exportSuffix 从 /post/{slug}.{ext} 里把 slug 和格式拆出来，没有对应后缀则返回 false。
+func exportSuffix(slug string) (base, format string, ok bool) {
+	for _, ext := range []string{".pdf", ".epub", ".html"} {
+		if strings.HasSuffix(slug, ext) {
+			return strings.TrimSuffix(slug, ext), strings.TrimPrefix(ext, "."), true
+		}
+	}
+	return slug, "", false
+}
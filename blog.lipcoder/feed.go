@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Atom/RSS/JSONFeed，方便订阅。getAllPosts() 已经按日期倒序排好了，直接遍历就行。
+const envBaseURL = "BLOG_BASE_URL"
+const defaultBaseURL = "http://localhost:8080"
+
+func baseURL() string {
+	if v := os.Getenv(envBaseURL); v != "" {
+		return strings.TrimSuffix(v, "/")
+	}
+	return defaultBaseURL
+}
+
+var tagsLineRegexp = regexp.MustCompile(`(?mi)^Tags:\s*(.+)$`)
+
+// postTags 从 markdown 正文里找一行 "Tags: foo, bar" 解析出标签列表，在 loadOnePost
+// 里作为没有 front matter（或者 front matter 没写 tags）时的兜底。
+func postTags(raw string) []string {
+	m := tagsLineRegexp.FindStringSubmatch(raw)
+	if len(m) < 2 {
+		return nil
+	}
+	var tags []string
+	for _, t := range strings.Split(m[1], ",") {
+		t = strings.TrimSpace(t)
+		if t != "" {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
+
+// absoluteImageURLs 把 post 渲染出来的 html 里 /images/... 的相对路径补成绝对 url，
+// 供 feed 阅读器直接加载图片。
+func absoluteImageURLs(htmlStr, base string) string {
+	return strings.ReplaceAll(htmlStr, `src="/images/`, `src="`+base+`/images/`)
+}
+
+func handleFeedAtom(w http.ResponseWriter, r *http.Request) {
+	base := baseURL()
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="utf-8"?>`+"\n")
+	fmt.Fprintf(w, `<feed xmlns="http://www.w3.org/2005/Atom">`+"\n")
+	posts := getAllPosts()
+	fmt.Fprintf(w, "<title>博客</title>\n<id>%s/</id>\n", base)
+	if len(posts) > 0 {
+		fmt.Fprintf(w, "<updated>%s</updated>\n", posts[0].Date.Format(time.RFC3339))
+	}
+	for _, p := range posts {
+		fmt.Fprintf(w, "<entry>\n")
+		fmt.Fprintf(w, "<title>%s</title>\n", xmlEscape(p.Title))
+		fmt.Fprintf(w, "<id>%s/post/%s</id>\n", base, p.Slug)
+		fmt.Fprintf(w, "<link href=\"%s/post/%s\"/>\n", base, p.Slug)
+		fmt.Fprintf(w, "<updated>%s</updated>\n", p.Date.Format(time.RFC3339))
+		fmt.Fprintf(w, "<content type=\"html\">%s</content>\n", xmlEscape(absoluteImageURLs(string(p.HTML), base)))
+		for _, tag := range p.Tags {
+			fmt.Fprintf(w, "<category term=\"%s\"/>\n", xmlEscape(tag))
+		}
+		fmt.Fprintf(w, "</entry>\n")
+	}
+	fmt.Fprintf(w, "</feed>\n")
+}
+
+func handleFeedRSS(w http.ResponseWriter, r *http.Request) {
+	base := baseURL()
+	w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?>`+"\n")
+	fmt.Fprintf(w, "<rss version=\"2.0\"><channel>\n<title>博客</title>\n<link>%s/</link>\n", base)
+	for _, p := range getAllPosts() {
+		fmt.Fprintf(w, "<item>\n")
+		fmt.Fprintf(w, "<title>%s</title>\n", xmlEscape(p.Title))
+		fmt.Fprintf(w, "<link>%s/post/%s</link>\n", base, p.Slug)
+		fmt.Fprintf(w, "<guid>%s/post/%s</guid>\n", base, p.Slug)
+		fmt.Fprintf(w, "<pubDate>%s</pubDate>\n", p.Date.Format(time.RFC1123Z))
+		fmt.Fprintf(w, "<description>%s</description>\n", xmlEscape(absoluteImageURLs(string(p.HTML), base)))
+		for _, tag := range p.Tags {
+			fmt.Fprintf(w, "<category>%s</category>\n", xmlEscape(tag))
+		}
+		fmt.Fprintf(w, "</item>\n")
+	}
+	fmt.Fprintf(w, "</channel></rss>\n")
+}
+
+// jsonFeedItem / jsonFeed 对应 https://www.jsonfeed.org/version/1.1/ 的结构。
+type jsonFeedItem struct {
+	ID            string   `json:"id"`
+	URL           string   `json:"url"`
+	Title         string   `json:"title"`
+	ContentHTML   string   `json:"content_html"`
+	DatePublished string   `json:"date_published"`
+	Tags          []string `json:"tags,omitempty"`
+}
+
+type jsonFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	FeedURL     string         `json:"feed_url"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+func handleFeedJSON(w http.ResponseWriter, r *http.Request) {
+	base := baseURL()
+	feed := jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       "博客",
+		HomePageURL: base + "/",
+		FeedURL:     base + "/feed.json",
+	}
+	for _, p := range getAllPosts() {
+		feed.Items = append(feed.Items, jsonFeedItem{
+			ID:            base + "/post/" + p.Slug,
+			URL:           base + "/post/" + p.Slug,
+			Title:         p.Title,
+			ContentHTML:   absoluteImageURLs(string(p.HTML), base),
+			DatePublished: p.Date.Format(time.RFC3339),
+			Tags:          p.Tags,
+		})
+	}
+	w.Header().Set("Content-Type", "application/feed+json; charset=utf-8")
+	_ = json.NewEncoder(w).Encode(feed)
+}
+
+func xmlEscape(s string) string {
+	replacer := strings.NewReplacer(
+		"&", "&amp;",
+		"<", "&lt;",
+		">", "&gt;",
+		`"`, "&quot;",
+	)
+	return replacer.Replace(s)
+}
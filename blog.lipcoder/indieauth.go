@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// verifyIndieAuthBearer 按 IndieAuth 规范把 Authorization: Bearer <token> 转发给
+// 配置好的 token endpoint 核验，返回核验到的 me 和 scope 列表。
+func verifyIndieAuthBearer(r *http.Request) (me string, scopes []string, err error) {
+	auth := r.Header.Get("Authorization")
+	token := strings.TrimSpace(strings.TrimPrefix(auth, "Bearer"))
+	if auth == "" || token == auth {
+		return "", nil, fmt.Errorf("missing bearer token")
+	}
+
+	endpoint := indieAuthTokenEndpoint()
+	if endpoint == "" {
+		return "", nil, fmt.Errorf("indieauth not configured")
+	}
+
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", nil, fmt.Errorf("token endpoint unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", nil, fmt.Errorf("token rejected by endpoint (%d)", resp.StatusCode)
+	}
+
+	var body struct {
+		Me       string `json:"me"`
+		Scope    string `json:"scope"`
+		ClientID string `json:"client_id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", nil, fmt.Errorf("invalid token endpoint response: %w", err)
+	}
+	if body.Me == "" {
+		return "", nil, fmt.Errorf("token endpoint did not return me")
+	}
+
+	return body.Me, strings.Fields(body.Scope), nil
+}
+
+func hasScope(scopes []string, want string) bool {
+	for _, s := range scopes {
+		if s == want {
+			return true
+		}
+	}
+	return false
+}
+
+func indieAuthTokenEndpoint() string {
+	ep := os.Getenv(envIndieAuthTokenEP)
+	if ep == "" {
+		return ""
+	}
+	if _, err := url.Parse(ep); err != nil {
+		return ""
+	}
+	return ep
+}
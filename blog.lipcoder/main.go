@@ -2,7 +2,6 @@ package main
 
 import (
 	"bytes"
-	"encoding/json"
 	"html/template"
 	"log"
 	"net/http"
@@ -10,7 +9,9 @@ import (
 	"path/filepath"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/yuin/goldmark"
@@ -34,6 +35,7 @@ type Post struct {
 	Summary string
 	Raw     string
 	HTML    template.HTML
+	Tags    []string
 }
 
 // Comment 表示一条评论
@@ -50,9 +52,14 @@ type CurrentUser struct {
 	AvatarURL  string
 }
 
-
 var (
-	tpl         *template.Template
+	tpl   *template.Template
+	tplMu sync.RWMutex
+
+	// postsMu 保护 postsBySlug / allPosts：markdown 文件本来就是在请求处理过程中
+	// （micropub 创建文章）或者 fsnotify 热加载的时候并发改写的，原来完全没锁，
+	// 是个现成的 data race。
+	postsMu     sync.RWMutex
 	postsBySlug map[string]*Post
 	allPosts    []*Post
 
@@ -71,6 +78,47 @@ var (
 	)
 )
 
+// getAllPosts / getPost / setPosts 是 allPosts / postsBySlug 的唯一合法访问方式，
+// 把读写都收进 postsMu 后面。
+func getAllPosts() []*Post {
+	postsMu.RLock()
+	defer postsMu.RUnlock()
+	return allPosts
+}
+
+func getPost(slug string) (*Post, bool) {
+	postsMu.RLock()
+	defer postsMu.RUnlock()
+	p, ok := postsBySlug[slug]
+	return p, ok
+}
+
+func setPosts(posts []*Post, bySlug map[string]*Post) {
+	postsMu.Lock()
+	defer postsMu.Unlock()
+	allPosts = posts
+	postsBySlug = bySlug
+}
+
+// renderTemplate 和 tpl.ExecuteTemplate 一样，只是把 tpl 指针的读取也收进锁里，
+// 这样 /-/reload 或者 fsnotify 重新解析模板的时候不会跟正在渲染的请求打架。
+func renderTemplate(w http.ResponseWriter, name string, data interface{}) error {
+	tplMu.RLock()
+	t := tpl
+	tplMu.RUnlock()
+	return t.ExecuteTemplate(w, name, data)
+}
+
+func setTemplates(t *template.Template) {
+	tplMu.Lock()
+	defer tplMu.Unlock()
+	tpl = t
+}
+
+func parseTemplates() (*template.Template, error) {
+	return template.ParseGlob(filepath.Join(templatesDir, "*.html"))
+}
+
 func main() {
 	// 加载 markdown
 	markdownDir := os.Getenv(envMarkdownDir)
@@ -78,22 +126,51 @@ func main() {
 		markdownDir = defaultMarkdownDir
 	}
 
-	var err error
-	allPosts, postsBySlug, err = loadPosts(markdownDir)
+	posts, bySlug, err := loadPosts(markdownDir)
 	if err != nil {
 		log.Fatalf("加载 markdown 失败: %v", err)
 	}
+	setPosts(posts, bySlug)
+
+	if err := openBlogStore(); err != nil {
+		log.Fatalf("打开 bbolt 存储失败: %v", err)
+	}
+	defer blogDB.Close()
+	migrateLegacyComments()
+	loadSpamWordLists()
+	detectConverter()
+	if err := buildSearchIndex(getAllPosts()); err != nil {
+		log.Fatalf("构建搜索索引失败: %v", err)
+	}
 
 	// 解析模板
-	tpl = template.Must(template.ParseGlob("templates/*.html"))
+	initialTpl, err := parseTemplates()
+	if err != nil {
+		log.Fatalf("解析模板失败: %v", err)
+	}
+	setTemplates(initialTpl)
+
+	// 监听 markdown 和模板目录，文件一变就热加载；容器里 inotify 不可靠的话，
+	// 这里会打日志但不影响启动，改用 /-/reload 手动触发。
+	if err := startWatcher(markdownDir); err != nil {
+		log.Printf("启动 fsnotify 监听失败，将只支持手动 /-/reload: %v", err)
+	}
 
 	// 路由
 	http.HandleFunc("/", handleIndex)
 	http.HandleFunc("/post/", handlePost)
 	http.HandleFunc("/about", handleAbout)
 	http.HandleFunc("/login", handleLogin)
+	http.HandleFunc("/login/callback", handleLoginCallback)
 	http.HandleFunc("/logout", handleLogout)
-
+	http.HandleFunc("/micropub", handleMicropub)
+	http.HandleFunc("/micropub/media", handleMicropubMedia)
+	http.HandleFunc("/admin/frozen", handleAdminFrozen)
+	http.HandleFunc("/feed.atom", handleFeedAtom)
+	http.HandleFunc("/feed.rss", handleFeedRSS)
+	http.HandleFunc("/feed.json", handleFeedJSON)
+	http.HandleFunc("/search", handleSearch)
+	http.HandleFunc("/-/reload", handleReload)
 
 	// markdown 图片静态文件：/images/... -> ./markdowns/images/...
 	http.Handle(
@@ -109,70 +186,28 @@ func main() {
 	log.Fatal(http.ListenAndServe(addr, nil))
 }
 
-// 首页：文章列表
+// 首页：文章列表；?bundle=zip 打包下载全部文章
 func handleIndex(w http.ResponseWriter, r *http.Request) {
 	if r.URL.Path != "/" {
 		http.NotFound(w, r)
 		return
 	}
+	if r.URL.Query().Get("bundle") == "zip" {
+		handleIndexBundle(w, r)
+		return
+	}
 	data := struct {
 		Posts []*Post
 	}{
-		Posts: allPosts,
+		Posts: getAllPosts(),
 	}
-	if err := tpl.ExecuteTemplate(w, "index.html", data); err != nil {
+	if err := renderTemplate(w, "index.html", data); err != nil {
 		log.Printf("渲染 index 失败: %v", err)
 	}
 }
 
-// 轻量 GitHub 登录：只记用户名到 cookie
-func handleLogin(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.NotFound(w, r)
-		return
-	}
-	if err := r.ParseForm(); err != nil {
-		http.Error(w, "表单解析失败", http.StatusBadRequest)
-		return
-	}
-	username := strings.TrimSpace(r.FormValue("github_name"))
-	next := r.FormValue("next")
-	if next == "" {
-		next = "/"
-	}
-
-	if username == "" {
-		// 懒得搞复杂错误码，直接跳回去
-		http.Redirect(w, r, next, http.StatusSeeOther)
-		return
-	}
-
-	http.SetCookie(w, &http.Cookie{
-		Name:     "github_user",
-		Value:    username,
-		Path:     "/",
-		Expires:  time.Now().Add(365 * 24 * time.Hour),
-		HttpOnly: true,
-	})
-
-	http.Redirect(w, r, next, http.StatusSeeOther)
-}
-
-// 注销：清掉 cookie
-func handleLogout(w http.ResponseWriter, r *http.Request) {
-	next := r.URL.Query().Get("next")
-	if next == "" {
-		next = "/"
-	}
-	http.SetCookie(w, &http.Cookie{
-		Name:   "github_user",
-		Value:  "",
-		Path:   "/",
-		MaxAge: -1,
-	})
-	http.Redirect(w, r, next, http.StatusSeeOther)
-}
-
+// handleLogin / handleLogout / currentUserFromRequest 现在都在 oauth.go 里，
+// 走真正的 GitHub OAuth 会话，而不是相信表单里填的用户名。
 
 // 关于页：纯静态介绍
 func handleAbout(w http.ResponseWriter, r *http.Request) {
@@ -180,13 +215,11 @@ func handleAbout(w http.ResponseWriter, r *http.Request) {
 		http.NotFound(w, r)
 		return
 	}
-	if err := tpl.ExecuteTemplate(w, "about.html", nil); err != nil {
+	if err := renderTemplate(w, "about.html", nil); err != nil {
 		log.Printf("渲染 about 失败: %v", err)
 	}
 }
 
-// 文章页 + 评论提交
-// 文章页 + 评论提交
 // 文章页 + 评论提交
 func handlePost(w http.ResponseWriter, r *http.Request) {
 	// 解析路径：/post/{slug} 或 /post/{slug}/comment
@@ -199,7 +232,20 @@ func handlePost(w http.ResponseWriter, r *http.Request) {
 	parts := strings.Split(path, "/")
 	slug := parts[0]
 
-	post, ok := postsBySlug[slug]
+	// 导出：/post/{slug}.pdf、.epub、.html
+	if len(parts) == 1 {
+		if base, format, ok := exportSuffix(slug); ok {
+			post, found := getPost(base)
+			if !found {
+				http.NotFound(w, r)
+				return
+			}
+			handlePostExport(w, r, post, format)
+			return
+		}
+	}
+
+	post, ok := getPost(slug)
 	if !ok {
 		http.NotFound(w, r)
 		return
@@ -234,6 +280,17 @@ func handlePost(w http.ResponseWriter, r *http.Request) {
 			c.Author = "匿名"
 		}
 
+		offender := requestOffender(r, currentUser)
+		if isFrozen(offender) {
+			http.Redirect(w, r, "/post/"+slug+"?err=frozen", http.StatusSeeOther)
+			return
+		}
+		if rejected, word := checkSpam(offender, c.Author+" "+c.Content); rejected {
+			log.Printf("评论命中敏感词 %q，拒绝，offender=%s", word, offender)
+			http.Error(w, "评论包含违禁内容", http.StatusBadRequest)
+			return
+		}
+
 		if err := appendComment(slug, c); err != nil {
 			log.Printf("写入评论失败: %v", err)
 			http.Redirect(w, r, "/post/"+slug+"?err=server", http.StatusSeeOther)
@@ -244,8 +301,10 @@ func handlePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 正常 GET 文章页
-	comments, _ := loadComments(slug)
+	// 正常 GET 文章页；?cursor=&limit= 控制评论分页
+	cursor := r.URL.Query().Get("cursor")
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	comments, nextCursor, _ := loadComments(slug, cursor, limit)
 	errKey := r.URL.Query().Get("err")
 	var errMsg string
 	switch errKey {
@@ -253,21 +312,25 @@ func handlePost(w http.ResponseWriter, r *http.Request) {
 		errMsg = "评论内容不能为空。"
 	case "server":
 		errMsg = "服务器写入失败，请稍后再试。"
+	case "frozen":
+		errMsg = "该账号已被冻结，无法发表评论。"
 	}
 
 	data := struct {
 		Post        *Post
 		Comments    []Comment
+		NextCursor  string
 		Error       string
 		CurrentUser *CurrentUser
 	}{
 		Post:        post,
 		Comments:    comments,
+		NextCursor:  nextCursor,
 		Error:       errMsg,
 		CurrentUser: currentUser,
 	}
 
-	if err := tpl.ExecuteTemplate(w, "post.html", data); err != nil {
+	if err := renderTemplate(w, "post.html", data); err != nil {
 		log.Printf("渲染 post 失败: %v", err)
 	}
 }
@@ -290,38 +353,13 @@ func loadPosts(root string) ([]*Post, map[string]*Post, error) {
 			return nil
 		}
 
-		data, err := os.ReadFile(path)
+		post, err := loadOnePost(path, root)
 		if err != nil {
 			return err
 		}
 
-		raw := string(data)
-		title := extractTitle(raw, name)
-		slug := makeSlug(path, root)
-		summary := makeSummary(raw)
-
-		info, err := d.Info()
-		modTime := time.Now()
-		if err == nil && info != nil {
-			modTime = info.ModTime()
-		}
-
-		htmlContent, err := renderMarkdown(raw)
-		if err != nil {
-			return err
-		}
-
-		post := &Post{
-			Slug:    slug,
-			Title:   title,
-			Date:    modTime,
-			Summary: summary,
-			Raw:     raw,
-			HTML:    htmlContent,
-		}
-
 		posts = append(posts, post)
-		postsBySlug[slug] = post
+		postsBySlug[post.Slug] = post
 		return nil
 	})
 
@@ -329,11 +367,140 @@ func loadPosts(root string) ([]*Post, map[string]*Post, error) {
 		return nil, nil, err
 	}
 
+	sortPostsByDate(posts)
+
+	return posts, postsBySlug, nil
+}
+
+// loadOnePost 解析单个 markdown 文件，fsnotify 热加载的时候也靠它只重新解析
+// 被改动的那一个文件，不用把整个目录扫一遍。
+func loadOnePost(path, root string) (*Post, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := string(data)
+	fm, body, hasFrontMatter := splitFrontMatter(raw)
+	if hasFrontMatter {
+		raw = body
+	}
+
+	title := fm.Title
+	if title == "" {
+		title = extractTitle(raw, filepath.Base(path))
+	}
+	slug := makeSlug(path, root)
+	summary := makeSummary(raw)
+
+	modTime := time.Now()
+	if info, err := os.Stat(path); err == nil {
+		modTime = info.ModTime()
+	}
+	if fm.HasDate {
+		modTime = fm.Date
+	}
+
+	htmlContent, err := renderMarkdown(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := fm.Tags
+	if tags == nil {
+		tags = postTags(raw)
+	}
+
+	return &Post{
+		Slug:    slug,
+		Title:   title,
+		Date:    modTime,
+		Summary: summary,
+		Raw:     raw,
+		HTML:    htmlContent,
+		Tags:    tags,
+	}, nil
+}
+
+func sortPostsByDate(posts []*Post) {
 	sort.Slice(posts, func(i, j int) bool {
 		return posts[i].Date.After(posts[j].Date)
 	})
+}
 
-	return posts, postsBySlug, nil
+// frontMatter 是 Micropub 创建文章时写在文件开头、用 --- 包起来的那几行元数据。
+type frontMatter struct {
+	Title   string
+	Date    time.Time
+	HasDate bool
+	Tags    []string
+}
+
+// splitFrontMatter 把文件开头 "---\n...\n---\n" 的 front matter 拆出来解析，剩下的
+// 正文原样返回。没有 front matter 的普通文件（ok=false）不受影响，走老的
+// extractTitle/Tags: 这行解析逻辑。
+func splitFrontMatter(raw string) (fm frontMatter, body string, ok bool) {
+	if !strings.HasPrefix(raw, "---\n") {
+		return frontMatter{}, raw, false
+	}
+	rest := raw[len("---\n"):]
+	end := strings.Index(rest, "\n---\n")
+	if end == -1 {
+		return frontMatter{}, raw, false
+	}
+	block := rest[:end]
+	body = rest[end+len("\n---\n"):]
+
+	for _, line := range strings.Split(block, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		i := strings.Index(line, ":")
+		if i == -1 {
+			continue
+		}
+		key := strings.TrimSpace(line[:i])
+		value := strings.TrimSpace(line[i+1:])
+		switch key {
+		case "title":
+			fm.Title = unquoteFrontMatterValue(value)
+		case "date":
+			if t, err := time.Parse(time.RFC3339, unquoteFrontMatterValue(value)); err == nil {
+				fm.Date = t
+				fm.HasDate = true
+			}
+		case "tags":
+			fm.Tags = parseFrontMatterList(value)
+		}
+	}
+	return fm, body, true
+}
+
+// unquoteFrontMatterValue 去掉 %q 写出来的双引号（strconv.Quote 的逆操作）。
+func unquoteFrontMatterValue(v string) string {
+	if unquoted, err := strconv.Unquote(v); err == nil {
+		return unquoted
+	}
+	return v
+}
+
+// parseFrontMatterList 解析 "[a, b, c]" 这种形式的标签列表。
+func parseFrontMatterList(v string) []string {
+	v = strings.TrimSpace(v)
+	v = strings.TrimPrefix(v, "[")
+	v = strings.TrimSuffix(v, "]")
+	if v == "" {
+		return nil
+	}
+	var out []string
+	for _, part := range strings.Split(v, ",") {
+		part = unquoteFrontMatterValue(strings.TrimSpace(part))
+		if part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
 }
 
 var titleRegexp = regexp.MustCompile(`(?m)^#\s+(.+)$`)
@@ -400,55 +567,3 @@ func renderMarkdown(content string) (template.HTML, error) {
 
 	return template.HTML(htmlStr), nil
 }
-
-// ----------------- 评论数据持久化 -----------------
-
-func commentsFilePath(slug string) string {
-	return filepath.Join(commentDir, slug+".json")
-}
-
-func loadComments(slug string) ([]Comment, error) {
-	path := commentsFilePath(slug)
-	data, err := os.ReadFile(path)
-	if os.IsNotExist(err) {
-		return []Comment{}, nil
-	}
-	if err != nil {
-		return nil, err
-	}
-	var cs []Comment
-	if err := json.Unmarshal(data, &cs); err != nil {
-		return nil, err
-	}
-	return cs, nil
-}
-
-func appendComment(slug string, c Comment) error {
-	cs, _ := loadComments(slug)
-	cs = append(cs, c)
-
-	if err := os.MkdirAll(commentDir, 0o755); err != nil {
-		return err
-	}
-	data, err := json.MarshalIndent(cs, "", "  ")
-	if err != nil {
-		return err
-	}
-	return os.WriteFile(commentsFilePath(slug), data, 0o644)
-}
-
-func currentUserFromRequest(r *http.Request) *CurrentUser {
-	c, err := r.Cookie("github_user")
-	if err != nil {
-		return nil
-	}
-	username := strings.TrimSpace(c.Value)
-	if username == "" {
-		return nil
-	}
-	return &CurrentUser{
-		GitHubUser: username,
-		AvatarURL:  "https://avatars.githubusercontent.com/" + username,
-	}
-}
-
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // 注册 webp 解码器
+)
+
+const (
+	envMediaDir          = "BLOG_MEDIA_DIR"
+	defaultMediaDir      = "markdowns/images"
+	envMediaJPEGQuality  = "MEDIA_JPEG_QUALITY"
+	envMediaMaxWidth     = "MEDIA_MAX_WIDTH"
+	defaultJPEGQuality   = 75
+	defaultMediaMaxWidth = 2000
+)
+
+// MediaStorage 让媒体后端可插拔：现在只有本地磁盘实现，以后可以加 S3/CDN。
+type MediaStorage interface {
+	Put(name string, r io.Reader) (url string, err error)
+}
+
+// localMediaStorage 把文件存到 markdowns/images/YYYY/MM/<hash>.<ext> 下面。
+type localMediaStorage struct {
+	root      string
+	publicURL string // 例如 /images，拼出来的 url 会是 publicURL + "/" + 相对路径
+}
+
+var mediaStorage MediaStorage = newLocalMediaStorage()
+
+func newLocalMediaStorage() MediaStorage {
+	root := os.Getenv(envMediaDir)
+	if root == "" {
+		root = defaultMediaDir
+	}
+	return &localMediaStorage{root: root, publicURL: "/images"}
+}
+
+func (s *localMediaStorage) Put(name string, r io.Reader) (string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+
+	ext := strings.ToLower(filepath.Ext(name))
+	data, ext, err = maybeCompressImage(data, ext)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])[:16]
+
+	now := time.Now()
+	relDir := filepath.Join(now.Format("2006"), now.Format("01"))
+	if err := os.MkdirAll(filepath.Join(s.root, relDir), 0o755); err != nil {
+		return "", err
+	}
+
+	relPath := filepath.Join(relDir, hash+ext)
+	fullPath := filepath.Join(s.root, relPath)
+	if err := os.WriteFile(fullPath, data, 0o644); err != nil {
+		return "", err
+	}
+
+	return s.publicURL + "/" + filepath.ToSlash(relPath), nil
+}
+
+// maybeCompressImage 重新编码 jpeg/png/webp：jpeg 按质量参数重新压缩，超宽的图片
+// 按最大宽度等比缩小。gif/svg 原样返回。
+func maybeCompressImage(data []byte, ext string) ([]byte, string, error) {
+	switch ext {
+	case ".jpg", ".jpeg", ".png", ".webp":
+	default:
+		return data, ext, nil
+	}
+
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		// 解不出来就原样存，不让一张坏图片把整个上传搞挂
+		return data, ext, nil
+	}
+
+	img = downscaleIfNeeded(img, mediaMaxWidth())
+
+	var buf bytes.Buffer
+	switch format {
+	case "png":
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), ".png", nil
+	default: // jpeg, webp -> 统一重编码成 jpeg
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality()}); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), ".jpg", nil
+	}
+}
+
+func downscaleIfNeeded(img image.Image, maxWidth int) image.Image {
+	b := img.Bounds()
+	width, height := b.Dx(), b.Dy()
+	if width <= maxWidth {
+		return img
+	}
+	newWidth := maxWidth
+	newHeight := height * newWidth / width
+	dst := image.NewRGBA(image.Rect(0, 0, newWidth, newHeight))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Over, nil)
+	return dst
+}
+
+func jpegQuality() int {
+	if v := os.Getenv(envMediaJPEGQuality); v != "" {
+		if q, err := strconv.Atoi(v); err == nil && q > 0 && q <= 100 {
+			return q
+		}
+	}
+	return defaultJPEGQuality
+}
+
+func mediaMaxWidth() int {
+	if v := os.Getenv(envMediaMaxWidth); v != "" {
+		if w, err := strconv.Atoi(v); err == nil && w > 0 {
+			return w
+		}
+	}
+	return defaultMediaMaxWidth
+}
+
+// handleMicropubMedia 实现 Micropub 的 media endpoint：接收 multipart 上传，
+// 存下来以后在 Location 头里返回公开 url。
+func handleMicropubMedia(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if _, scopes, err := verifyIndieAuthBearer(r); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	} else if !hasScope(scopes, micropubScope) && !hasScope(scopes, "media") {
+		http.Error(w, "token missing create/media scope", http.StatusForbidden)
+		return
+	}
+
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		http.Error(w, "invalid multipart body", http.StatusBadRequest)
+		return
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "missing file field", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	url, err := mediaStorage.Put(header.Filename, file)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to store media: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Location", url)
+	w.WriteHeader(http.StatusCreated)
+}
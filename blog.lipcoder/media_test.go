@@ -0,0 +1,35 @@
+package main
+
+import (
+	"image"
+	"testing"
+)
+
+func TestDownscaleIfNeededNoOpBelowMaxWidth(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 800, 600))
+	out := downscaleIfNeeded(img, 2000)
+	if out.Bounds() != img.Bounds() {
+		t.Fatalf("downscaleIfNeeded() changed bounds for an image under maxWidth: got %v, want %v", out.Bounds(), img.Bounds())
+	}
+}
+
+func TestDownscaleIfNeededPreservesAspectRatio(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4000, 2000))
+	out := downscaleIfNeeded(img, 2000)
+
+	b := out.Bounds()
+	if b.Dx() != 2000 {
+		t.Fatalf("downscaleIfNeeded() width = %d, want 2000", b.Dx())
+	}
+	if b.Dy() != 1000 {
+		t.Fatalf("downscaleIfNeeded() height = %d, want 1000 (aspect ratio preserved)", b.Dy())
+	}
+}
+
+func TestDownscaleIfNeededExactlyAtMaxWidth(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2000, 1500))
+	out := downscaleIfNeeded(img, 2000)
+	if out.Bounds() != img.Bounds() {
+		t.Fatalf("downscaleIfNeeded() changed bounds for an image exactly at maxWidth: got %v, want %v", out.Bounds(), img.Bounds())
+	}
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Micropub (https://www.w3.org/TR/micropub/) 让 Quill 之类的客户端可以
+// 不登 SSH 直接发文章。当前只支持创建 h-entry，不支持 update/delete。
+const (
+	micropubScope       = "create"
+	envIndieAuthTokenEP = "INDIEAUTH_TOKEN_ENDPOINT"
+)
+
+// micropubEntry 是表单/JSON 两种请求体解析后的统一中间表示。
+type micropubEntry struct {
+	Name      string
+	Content   string
+	Category  []string
+	Published string
+	Slug      string
+	Photo     []string
+	LikeOf    string
+}
+
+func handleMicropub(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		handleMicropubQuery(w, r)
+	case http.MethodPost:
+		handleMicropubCreate(w, r)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func handleMicropubQuery(w http.ResponseWriter, r *http.Request) {
+	if _, _, err := verifyIndieAuthBearer(r); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	switch r.URL.Query().Get("q") {
+	case "config":
+		// mediaStorage 永远有一个能用的本地磁盘后端，/micropub/media 也是无条件
+		// 注册的，所以这里直接、永远地把它报出去，不再靠一个跟路由注册脱节的
+		// 环境变量来决定要不要广播。
+		cfg := map[string]interface{}{
+			"q":              []string{"config", "source"},
+			"media-endpoint": baseURL() + "/micropub/media",
+		}
+		writeJSON(w, http.StatusOK, cfg)
+
+	case "source":
+		url := r.URL.Query().Get("url")
+		slug := slugFromPostURL(url)
+		post, ok := getPost(slug)
+		if !ok {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"type": []string{"h-entry"},
+			"properties": map[string]interface{}{
+				"name":    []string{post.Title},
+				"content": []string{post.Raw},
+			},
+		})
+
+	default:
+		http.Error(w, "unsupported q", http.StatusBadRequest)
+	}
+}
+
+func handleMicropubCreate(w http.ResponseWriter, r *http.Request) {
+	_, scopes, err := verifyIndieAuthBearer(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if !hasScope(scopes, micropubScope) {
+		http.Error(w, "token missing create scope", http.StatusForbidden)
+		return
+	}
+
+	entry, err := parseMicropubRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// like-of 允许没有 content：这是一条“点赞”帖，正文留空即可。
+	if entry.Content == "" && entry.LikeOf == "" && entry.Name == "" {
+		http.Error(w, "empty post", http.StatusBadRequest)
+		return
+	}
+
+	slug, err := createPostFromMicropub(entry)
+	if err != nil {
+		http.Error(w, "failed to create post: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Location", "/post/"+slug)
+	w.WriteHeader(http.StatusCreated)
+}
+
+// parseMicropubRequest 按 Content-Type 分流到 form / json 两种解析方式。
+func parseMicropubRequest(r *http.Request) (micropubEntry, error) {
+	ct := r.Header.Get("Content-Type")
+	if strings.HasPrefix(ct, "application/json") {
+		return parseMicropubJSON(r)
+	}
+	return parseMicropubForm(r)
+}
+
+func parseMicropubForm(r *http.Request) (micropubEntry, error) {
+	if err := r.ParseForm(); err != nil {
+		return micropubEntry{}, err
+	}
+	if h := r.FormValue("h"); h != "" && h != "entry" {
+		return micropubEntry{}, fmt.Errorf("unsupported h=%s", h)
+	}
+	return micropubEntry{
+		Name:      strings.TrimSpace(r.FormValue("name")),
+		Content:   strings.TrimSpace(r.FormValue("content")),
+		Category:  r.Form["category[]"],
+		Published: strings.TrimSpace(r.FormValue("published")),
+		Slug:      strings.TrimSpace(r.FormValue("slug")),
+		Photo:     r.Form["photo"],
+		LikeOf:    strings.TrimSpace(r.FormValue("like-of")),
+	}, nil
+}
+
+// micropubJSONBody 对应 JSON 形式的 Micropub 请求：
+// {"type":["h-entry"],"properties":{"content":["..."], ...}}
+type micropubJSONBody struct {
+	Type       []string            `json:"type"`
+	Properties map[string][]string `json:"properties"`
+}
+
+func parseMicropubJSON(r *http.Request) (micropubEntry, error) {
+	var body micropubJSONBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return micropubEntry{}, err
+	}
+	if len(body.Type) > 0 && body.Type[0] != "h-entry" {
+		return micropubEntry{}, fmt.Errorf("unsupported type=%s", body.Type[0])
+	}
+	first := func(key string) string {
+		if v := body.Properties[key]; len(v) > 0 {
+			return strings.TrimSpace(v[0])
+		}
+		return ""
+	}
+	return micropubEntry{
+		Name:      first("name"),
+		Content:   first("content"),
+		Category:  body.Properties["category"],
+		Published: first("published"),
+		Slug:      first("slug"),
+		Photo:     body.Properties["photo"],
+		LikeOf:    first("like-of"),
+	}, nil
+}
+
+// createPostFromMicropub 写一个带 front-matter 的 markdown 文件，然后重新跑一遍
+// loadPosts 把 postsBySlug / allPosts 刷新出来。
+func createPostFromMicropub(entry micropubEntry) (string, error) {
+	markdownDir := os.Getenv(envMarkdownDir)
+	if markdownDir == "" {
+		markdownDir = defaultMarkdownDir
+	}
+
+	published := time.Now()
+	if entry.Published != "" {
+		if t, err := time.Parse(time.RFC3339, entry.Published); err == nil {
+			published = t
+		}
+	}
+
+	title := entry.Name
+	if title == "" && entry.LikeOf != "" {
+		title = "Like: " + entry.LikeOf
+	}
+	if title == "" {
+		title = "Untitled"
+	}
+
+	slug := entry.Slug
+	if slug == "" {
+		slug = slugify(title)
+	}
+	if slug == "" {
+		slug = "post-" + strconv.FormatInt(published.UnixNano(), 36)
+	}
+
+	var fm strings.Builder
+	fm.WriteString("---\n")
+	fmt.Fprintf(&fm, "title: %q\n", title)
+	fm.WriteString("date: " + published.Format(time.RFC3339) + "\n")
+	fmt.Fprintf(&fm, "slug: %q\n", slug)
+	if len(entry.Category) > 0 {
+		fm.WriteString("tags: [" + strings.Join(quoteAll(entry.Category), ", ") + "]\n")
+	}
+	fm.WriteString("---\n\n")
+
+	body := entry.Content
+	if body == "" && entry.LikeOf != "" {
+		body = "Liked: " + entry.LikeOf
+	}
+	fm.WriteString("# " + title + "\n\n" + body + "\n")
+
+	path := filepath.Join(markdownDir, slug+".md")
+	if err := os.WriteFile(path, []byte(fm.String()), 0o644); err != nil {
+		return "", err
+	}
+
+	posts, bySlug, err := loadPosts(markdownDir)
+	if err != nil {
+		return "", err
+	}
+	setPosts(posts, bySlug)
+
+	if post, ok := getPost(slug); ok {
+		if err := indexPost(post); err != nil {
+			return "", err
+		}
+	}
+
+	return slug, nil
+}
+
+func quoteAll(ss []string) []string {
+	out := make([]string, len(ss))
+	for i, s := range ss {
+		out[i] = strconv.Quote(s)
+	}
+	return out
+}
+
+func slugify(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	var b strings.Builder
+	lastDash := false
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z' || r >= '0' && r <= '9':
+			b.WriteRune(r)
+			lastDash = false
+		default:
+			if !lastDash {
+				b.WriteByte('-')
+				lastDash = true
+			}
+		}
+	}
+	return strings.Trim(b.String(), "-")
+}
+
+func slugFromPostURL(url string) string {
+	i := strings.Index(url, "/post/")
+	if i == -1 {
+		return url
+	}
+	return strings.Trim(url[i+len("/post/"):], "/")
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
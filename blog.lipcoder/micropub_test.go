@@ -0,0 +1,39 @@
+package main
+
+import "testing"
+
+func TestSlugify(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{"Hello World", "hello-world"},
+		{"  Leading and trailing  ", "leading-and-trailing"},
+		{"Already-slugged", "already-slugged"},
+		{"Multiple   Spaces", "multiple-spaces"},
+		{"Punctuation! Is? Stripped.", "punctuation-is-stripped"},
+		{"", ""},
+		{"???", ""},
+	}
+	for _, c := range cases {
+		if got := slugify(c.in); got != c.want {
+			t.Errorf("slugify(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+func TestSlugFromPostURL(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{"https://example.com/post/hello-world", "hello-world"},
+		{"https://example.com/post/hello-world/", "hello-world"},
+		{"hello-world", "hello-world"},
+	}
+	for _, c := range cases {
+		if got := slugFromPostURL(c.in); got != c.want {
+			t.Errorf("slugFromPostURL(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
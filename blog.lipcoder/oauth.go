@@ -0,0 +1,409 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// GitHub OAuth 2.0 授权码流程，替换掉原来“表单里填个 github_name 就信了”的假登录。
+// /login 跳去 GitHub 授权页，/login/callback 换 token、拉用户信息、建会话。
+const (
+	envGitHubClientID     = "GITHUB_CLIENT_ID"
+	envGitHubClientSecret = "GITHUB_CLIENT_SECRET"
+	envGitHubRedirectURL  = "GITHUB_REDIRECT_URL"
+	envDevLogin           = "DEV_LOGIN"
+	envSessionSecret      = "SESSION_SECRET"
+
+	githubAuthorizeURL   = "https://github.com/login/oauth/authorize"
+	githubAccessTokenURL = "https://github.com/login/oauth/access_token"
+	githubUserAPIURL     = "https://api.github.com/user"
+
+	stateCookieName   = "oauth_state"
+	sessionCookieName = "session_id"
+	bucketSessions    = "sessions"
+
+	sessionTTL = 30 * 24 * time.Hour
+)
+
+// sessionRecord 是存进 bbolt 的会话内容：已验证过的 GitHub 身份 + 过期时间。
+type sessionRecord struct {
+	GitHubUser string    `json:"github_user"`
+	AvatarURL  string    `json:"avatar_url"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// sessionSecret 用来给 state / session cookie 签名，防止客户端伪造。没配置的话
+// 启动时随机生成一份，代价是重启会让所有会话失效——对这种规模的博客可以接受。
+var sessionSecret = loadOrGenerateSessionSecret()
+
+func loadOrGenerateSessionSecret() []byte {
+	if v := os.Getenv(envSessionSecret); v != "" {
+		return []byte(v)
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		log.Fatalf("failed to generate session secret: %v", err)
+	}
+	log.Printf("警告：未配置 %s，本次运行使用随机生成的会话密钥，重启后所有会话失效", envSessionSecret)
+	return b
+}
+
+func sign(value string) string {
+	mac := hmac.New(sha256.New, sessionSecret)
+	mac.Write([]byte(value))
+	return value + "." + hex.EncodeToString(mac.Sum(nil))
+}
+
+func verifySigned(signed string) (string, bool) {
+	i := strings.LastIndex(signed, ".")
+	if i < 0 {
+		return "", false
+	}
+	value, sig := signed[:i], signed[i+1:]
+	mac := hmac.New(sha256.New, sessionSecret)
+	mac.Write([]byte(value))
+	expected := hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(sig), []byte(expected)) {
+		return "", false
+	}
+	return value, true
+}
+
+func randomToken() string {
+	b := make([]byte, 24)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func devLoginEnabled() bool {
+	return os.Getenv(envDevLogin) == "1"
+}
+
+// safeNextPath 校验 ?next= 只能是站内相对路径，拒绝 "//evil.com"、
+// "/\evil.com"、"https://evil.com" 这类会被浏览器当成跨站跳转的写法，
+// 避免登录/登出链接被用来做开放重定向钓鱼。
+func safeNextPath(next string) string {
+	if next == "" || next[0] != '/' {
+		return "/"
+	}
+	if len(next) > 1 && (next[1] == '/' || next[1] == '\\') {
+		return "/"
+	}
+	return next
+}
+
+// handleLogin：GET 发起真正的 GitHub OAuth 流程；POST 只在 DEV_LOGIN=1 时保留
+// 老的“表单里填用户名就信了”的开发模式，方便本地联调不用配 OAuth app。
+func handleLogin(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		handleLoginStart(w, r)
+	case http.MethodPost:
+		if !devLoginEnabled() {
+			http.Error(w, "dev login disabled", http.StatusForbidden)
+			return
+		}
+		handleDevLogin(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func handleLoginStart(w http.ResponseWriter, r *http.Request) {
+	clientID := os.Getenv(envGitHubClientID)
+	if clientID == "" {
+		http.Error(w, "GitHub OAuth 未配置", http.StatusInternalServerError)
+		return
+	}
+
+	next := safeNextPath(r.URL.Query().Get("next"))
+	state := randomToken()
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     stateCookieName,
+		Value:    sign(state + "|" + next),
+		Path:     "/",
+		Expires:  time.Now().Add(10 * time.Minute),
+		HttpOnly: true,
+	})
+
+	q := url.Values{}
+	q.Set("client_id", clientID)
+	q.Set("scope", "read:user")
+	q.Set("state", state)
+	if redirect := os.Getenv(envGitHubRedirectURL); redirect != "" {
+		q.Set("redirect_uri", redirect)
+	}
+
+	http.Redirect(w, r, githubAuthorizeURL+"?"+q.Encode(), http.StatusSeeOther)
+}
+
+func handleLoginCallback(w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie(stateCookieName)
+	if err != nil {
+		http.Error(w, "missing state cookie", http.StatusBadRequest)
+		return
+	}
+	raw, ok := verifySigned(cookie.Value)
+	if !ok {
+		http.Error(w, "invalid state cookie", http.StatusBadRequest)
+		return
+	}
+	parts := strings.SplitN(raw, "|", 2)
+	wantState, next := parts[0], "/"
+	if len(parts) == 2 {
+		next = parts[1]
+	}
+
+	if r.URL.Query().Get("state") != wantState {
+		http.Error(w, "state mismatch", http.StatusBadRequest)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: stateCookieName, Value: "", Path: "/", MaxAge: -1})
+
+	code := r.URL.Query().Get("code")
+	if code == "" {
+		http.Error(w, "missing code", http.StatusBadRequest)
+		return
+	}
+
+	token, err := exchangeGitHubCode(code)
+	if err != nil {
+		log.Printf("GitHub token 交换失败: %v", err)
+		http.Error(w, "login failed", http.StatusBadGateway)
+		return
+	}
+
+	login, avatar, err := fetchGitHubUser(token)
+	if err != nil {
+		log.Printf("拉取 GitHub 用户信息失败: %v", err)
+		http.Error(w, "login failed", http.StatusBadGateway)
+		return
+	}
+
+	sessionID, err := createSession(login, avatar)
+	if err != nil {
+		log.Printf("创建会话失败: %v", err)
+		http.Error(w, "login failed", http.StatusInternalServerError)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    sign(sessionID),
+		Path:     "/",
+		Expires:  time.Now().Add(sessionTTL),
+		HttpOnly: true,
+	})
+
+	http.Redirect(w, r, next, http.StatusSeeOther)
+}
+
+func exchangeGitHubCode(code string) (string, error) {
+	form := url.Values{}
+	form.Set("client_id", os.Getenv(envGitHubClientID))
+	form.Set("client_secret", os.Getenv(envGitHubClientSecret))
+	form.Set("code", code)
+	if redirect := os.Getenv(envGitHubRedirectURL); redirect != "" {
+		form.Set("redirect_uri", redirect)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, githubAccessTokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	var tokenResp struct {
+		AccessToken string `json:"access_token"`
+		Error       string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &tokenResp); err != nil {
+		return "", fmt.Errorf("unexpected token response: %s", body)
+	}
+	if tokenResp.Error != "" || tokenResp.AccessToken == "" {
+		return "", fmt.Errorf("github returned error: %s", tokenResp.Error)
+	}
+	return tokenResp.AccessToken, nil
+}
+
+func fetchGitHubUser(token string) (login, avatarURL string, err error) {
+	req, err := http.NewRequest(http.MethodGet, githubUserAPIURL, nil)
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("github /user returned %d", resp.StatusCode)
+	}
+
+	var user struct {
+		Login     string `json:"login"`
+		ID        int64  `json:"id"`
+		AvatarURL string `json:"avatar_url"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
+		return "", "", err
+	}
+	if user.Login == "" {
+		return "", "", fmt.Errorf("github /user did not return a login")
+	}
+	return user.Login, user.AvatarURL, nil
+}
+
+func createSession(githubUser, avatarURL string) (string, error) {
+	rec := sessionRecord{
+		GitHubUser: githubUser,
+		AvatarURL:  avatarURL,
+		ExpiresAt:  time.Now().Add(sessionTTL),
+	}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return "", err
+	}
+	id := randomToken()
+	err = blogDB.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(bucketSessions))
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(id), data)
+	})
+	return id, err
+}
+
+func deleteSession(id string) error {
+	return blogDB.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucketSessions))
+		if b == nil {
+			return nil
+		}
+		return b.Delete([]byte(id))
+	})
+}
+
+func sessionByID(id string) (*sessionRecord, bool) {
+	var rec sessionRecord
+	found := false
+	_ = blogDB.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucketSessions))
+		if b == nil {
+			return nil
+		}
+		data := b.Get([]byte(id))
+		if data == nil {
+			return nil
+		}
+		if err := json.Unmarshal(data, &rec); err != nil {
+			return nil
+		}
+		found = true
+		return nil
+	})
+	if !found || time.Now().After(rec.ExpiresAt) {
+		return nil, false
+	}
+	return &rec, true
+}
+
+// handleDevLogin 是老的“表单里填用户名就信了”的假登录，只在 DEV_LOGIN=1 时可用，
+// 方便本地跑起来点点点，不用申请一个 GitHub OAuth app。
+func handleDevLogin(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "表单解析失败", http.StatusBadRequest)
+		return
+	}
+	username := strings.TrimSpace(r.FormValue("github_name"))
+	next := safeNextPath(r.FormValue("next"))
+	if username == "" {
+		http.Redirect(w, r, next, http.StatusSeeOther)
+		return
+	}
+
+	id, err := createSession(username, "https://avatars.githubusercontent.com/"+username)
+	if err != nil {
+		http.Error(w, "创建会话失败", http.StatusInternalServerError)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    sign(id),
+		Path:     "/",
+		Expires:  time.Now().Add(sessionTTL),
+		HttpOnly: true,
+	})
+	http.Redirect(w, r, next, http.StatusSeeOther)
+}
+
+// 注销：把会话从 bbolt 里删掉，再清 cookie。只清 cookie 的话，提前泄露出去的
+// session_id（XSS、共享电脑、日志泄漏）在 30 天 TTL 内还能一直用，等于没注销。
+func handleLogout(w http.ResponseWriter, r *http.Request) {
+	next := safeNextPath(r.URL.Query().Get("next"))
+	if cookie, err := r.Cookie(sessionCookieName); err == nil {
+		if id, ok := verifySigned(cookie.Value); ok {
+			if err := deleteSession(id); err != nil {
+				log.Printf("删除会话失败: %v", err)
+			}
+		}
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:   sessionCookieName,
+		Value:  "",
+		Path:   "/",
+		MaxAge: -1,
+	})
+	http.Redirect(w, r, next, http.StatusSeeOther)
+}
+
+func currentUserFromRequest(r *http.Request) *CurrentUser {
+	cookie, err := r.Cookie(sessionCookieName)
+	if err != nil {
+		return nil
+	}
+	id, ok := verifySigned(cookie.Value)
+	if !ok {
+		return nil
+	}
+	rec, ok := sessionByID(id)
+	if !ok {
+		return nil
+	}
+	return &CurrentUser{
+		GitHubUser: rec.GitHubUser,
+		AvatarURL:  rec.AvatarURL,
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import "testing"
+
+func TestSignVerifySignedRoundTrip(t *testing.T) {
+	signed := sign("state123|/post/hello")
+	value, ok := verifySigned(signed)
+	if !ok {
+		t.Fatalf("verifySigned(%q) = _, false, want true", signed)
+	}
+	if value != "state123|/post/hello" {
+		t.Fatalf("verifySigned(%q) = %q, want %q", signed, value, "state123|/post/hello")
+	}
+}
+
+func TestVerifySignedRejectsTamperedValue(t *testing.T) {
+	signed := sign("state123|/")
+	tampered := "state456|/evil" + signed[len("state123|/"):]
+	if _, ok := verifySigned(tampered); ok {
+		t.Fatalf("verifySigned(%q) = _, true, want false (tampered value)", tampered)
+	}
+}
+
+func TestVerifySignedRejectsMissingSignature(t *testing.T) {
+	if _, ok := verifySigned("no-dot-in-here"); ok {
+		t.Fatal("verifySigned(no signature) = true, want false")
+	}
+}
+
+func TestSafeNextPath(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{"", "/"},
+		{"/post/hello", "/post/hello"},
+		{"//evil.example", "/"},
+		{"/\\evil.example", "/"},
+		{"https://evil.example", "/"},
+		{"evil.example", "/"},
+	}
+	for _, c := range cases {
+		if got := safeNextPath(c.in); got != c.want {
+			t.Errorf("safeNextPath(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
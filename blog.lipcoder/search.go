@@ -0,0 +1,123 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/blevesearch/bleve/v2"
+)
+
+// 全文搜索：loadPosts 的时候把 Raw+Title 灌进一个内存 bleve 索引，GET /search?q=
+// 用 bleve 自带的 highlighter 标出命中片段。
+var (
+	searchIndexMu sync.RWMutex
+	searchIndex   bleve.Index
+)
+
+// searchDoc 是灌进 bleve 的文档结构。
+type searchDoc struct {
+	Title string
+	Raw   string
+}
+
+func buildSearchIndex(posts []*Post) error {
+	mapping := bleve.NewIndexMapping()
+	idx, err := bleve.NewMemOnly(mapping)
+	if err != nil {
+		return err
+	}
+	for _, p := range posts {
+		if err := idx.Index(p.Slug, searchDoc{Title: p.Title, Raw: p.Raw}); err != nil {
+			return err
+		}
+	}
+
+	searchIndexMu.Lock()
+	old := searchIndex
+	searchIndex = idx
+	searchIndexMu.Unlock()
+
+	if old != nil {
+		_ = old.Close()
+	}
+	return nil
+}
+
+// indexPost 增量更新单篇文章，不用把整个索引重建一遍。
+func indexPost(post *Post) error {
+	searchIndexMu.RLock()
+	idx := searchIndex
+	searchIndexMu.RUnlock()
+	if idx == nil {
+		return nil
+	}
+	return idx.Index(post.Slug, searchDoc{Title: post.Title, Raw: post.Raw})
+}
+
+// deletePostFromIndex 把被删掉/改名的文章从搜索索引里摘掉，跟 indexPost 配对，
+// 这样 fsnotify 热加载不用每次改动都把整个索引重建一遍。
+func deletePostFromIndex(slug string) error {
+	searchIndexMu.RLock()
+	idx := searchIndex
+	searchIndexMu.RUnlock()
+	if idx == nil {
+		return nil
+	}
+	return idx.Delete(slug)
+}
+
+type searchResult struct {
+	Slug       string   `json:"slug"`
+	Title      string   `json:"title"`
+	Snippet    string   `json:"snippet"`
+	Highlights []string `json:"highlights"`
+}
+
+func handleSearch(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query().Get("q")
+	if q == "" {
+		writeJSON(w, http.StatusOK, []searchResult{})
+		return
+	}
+
+	searchIndexMu.RLock()
+	idx := searchIndex
+	searchIndexMu.RUnlock()
+	if idx == nil {
+		writeJSON(w, http.StatusOK, []searchResult{})
+		return
+	}
+
+	query := bleve.NewQueryStringQuery(q)
+	req := bleve.NewSearchRequest(query)
+	req.Highlight = bleve.NewHighlight()
+	req.Fields = []string{"Title"}
+
+	res, err := idx.Search(req)
+	if err != nil {
+		http.Error(w, "search failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	results := make([]searchResult, 0, len(res.Hits))
+	for _, hit := range res.Hits {
+		post, ok := getPost(hit.ID)
+		if !ok {
+			continue
+		}
+
+		var highlights []string
+		for _, fragments := range hit.Fragments {
+			highlights = append(highlights, fragments...)
+		}
+
+		results = append(results, searchResult{
+			Slug:       post.Slug,
+			Title:      post.Title,
+			Snippet:    post.Summary,
+			Highlights: highlights,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, results)
+}
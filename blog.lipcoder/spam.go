@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// 评论防刷：标题级词表命中直接拒绝，同一个 GitHub 用户/IP 在滑动窗口内多次命中
+// 就把账号冻结掉，冻结状态落盘，/admin/frozen 可以看/解冻。
+const (
+	sensitiveTitleListPath   = "./data/sensitive_title.txt"
+	sensitiveContentListPath = "./data/sensitive_content.txt"
+
+	bucketFrozen   = "frozen"
+	bucketSpamHits = "spam_hits"
+
+	freezeWindow    = 10 * time.Minute
+	freezeThreshold = 3
+)
+
+var (
+	titleMatcher   *ACMatcher
+	contentMatcher *ACMatcher
+)
+
+func loadSpamWordLists() {
+	titleMatcher = buildAC(readWordList(sensitiveTitleListPath))
+	contentMatcher = buildAC(readWordList(sensitiveContentListPath))
+}
+
+func readWordList(path string) []string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var words []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, normalizeForSpamCheck(line))
+	}
+	return words
+}
+
+// checkSpam 核验一个字段，命中标题词表就拒绝；命中内容词表只记录，不拒绝。
+// offender 是 GitHub 用户名（没登录就用 IP）。
+func checkSpam(offender, field string) (rejected bool, matched string) {
+	normalized := normalizeForSpamCheck(field)
+	if titleMatcher != nil {
+		if word, ok := titleMatcher.Scan(normalized); ok {
+			recordSpamHit(offender)
+			return true, word
+		}
+	}
+	if contentMatcher != nil {
+		if word, ok := contentMatcher.Scan(normalized); ok {
+			recordSpamHit(offender)
+			return false, word
+		}
+	}
+	return false, ""
+}
+
+// requestOffender 是垃圾识别/冻结用的 key：登录用户用真实 GitHub 身份，没登录的
+// 绝不能信任客户端自己填的 author——不然谁都能填 author=victim 嫁祸给别人，等
+// victim 真的用 chunk0-4 加的 OAuth 登录时就会被连坐冻结。统一退回到 IP。
+func requestOffender(r *http.Request, currentUser *CurrentUser) string {
+	if currentUser != nil {
+		return "gh:" + currentUser.GitHubUser
+	}
+	return "ip:" + remoteHost(r)
+}
+
+// remoteHost 去掉 r.RemoteAddr 里的端口，只留 host——端口是每条 TCP 连接分配的，
+// 同一个人开个新连接/新标签页端口就变了，带着端口去记 freeze key 等于从来不会
+// 真正累计命中次数。
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func recordSpamHit(offender string) {
+	now := time.Now()
+	_ = blogDB.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(bucketSpamHits))
+		if err != nil {
+			return err
+		}
+		var hits []int64
+		if data := b.Get([]byte(offender)); data != nil {
+			_ = json.Unmarshal(data, &hits)
+		}
+		cutoff := now.Add(-freezeWindow).UnixNano()
+		fresh := hits[:0]
+		for _, h := range hits {
+			if h >= cutoff {
+				fresh = append(fresh, h)
+			}
+		}
+		fresh = append(fresh, now.UnixNano())
+		data, _ := json.Marshal(fresh)
+		if err := b.Put([]byte(offender), data); err != nil {
+			return err
+		}
+		if len(fresh) >= freezeThreshold {
+			return freezeOffender(tx, offender)
+		}
+		return nil
+	})
+}
+
+func freezeOffender(tx *bolt.Tx, offender string) error {
+	b, err := tx.CreateBucketIfNotExists([]byte(bucketFrozen))
+	if err != nil {
+		return err
+	}
+	return b.Put([]byte(offender), []byte(time.Now().Format(time.RFC3339)))
+}
+
+func isFrozen(offender string) bool {
+	frozen := false
+	_ = blogDB.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucketFrozen))
+		if b == nil {
+			return nil
+		}
+		frozen = b.Get([]byte(offender)) != nil
+		return nil
+	})
+	return frozen
+}
+
+func unfreezeOffender(offender string) error {
+	return blogDB.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucketFrozen))
+		if b == nil {
+			return nil
+		}
+		return b.Delete([]byte(offender))
+	})
+}
+
+// handleAdminFrozen 是 basic-auth 保护下的冻结名单管理页：GET 看列表，
+// POST ?unfreeze=<name> 解冻。
+func handleAdminFrozen(w http.ResponseWriter, r *http.Request) {
+	if !checkAdminBasicAuth(r) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="admin"`)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		if name := r.URL.Query().Get("unfreeze"); name != "" {
+			if err := unfreezeOffender(name); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+
+	var frozen []string
+	_ = blogDB.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucketFrozen))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			frozen = append(frozen, string(k))
+			return nil
+		})
+	})
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"frozen": frozen})
+}
+
+func checkAdminBasicAuth(r *http.Request) bool {
+	user, pass, ok := r.BasicAuth()
+	if !ok {
+		return false
+	}
+	wantUser := os.Getenv("ADMIN_USER")
+	wantPass := os.Getenv("ADMIN_PASSWORD")
+	if wantUser == "" || wantPass == "" {
+		return false
+	}
+	return user == wantUser && pass == wantPass
+}
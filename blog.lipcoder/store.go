@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// 评论原来是 ./data/comments/<slug>.json 一个文件一个文件地全量读写，热门文章的
+// 评论区会把同一篇文章的所有请求都卡在一把锁后面。换成 bbolt：顶层 "comments"
+// bucket 下面每篇文章一个嵌套 bucket，key 用单调 ULID，天然按时间排序，
+// 分页也不用每次把整篇都读出来再切片。
+const (
+	blogDBPath      = "./data/blog.db"
+	bucketComments  = "comments"
+	defaultPageSize = 20
+)
+
+var blogDB *bolt.DB
+
+func openBlogStore() error {
+	if err := os.MkdirAll("./data", 0o755); err != nil {
+		return err
+	}
+	var err error
+	blogDB, err = bolt.Open(blogDBPath, 0o600, nil)
+	if err != nil {
+		return err
+	}
+	return blogDB.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(bucketComments))
+		return err
+	})
+}
+
+// loadComments 返回 slug 对应的评论，按 cursor 之后的 limit 条分页；cursor 为空
+// 表示从头开始。返回值里的 nextCursor 为空字符串表示没有更多了。
+func loadComments(slug, cursor string, limit int) (comments []Comment, nextCursor string, err error) {
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+	err = blogDB.View(func(tx *bolt.Tx) error {
+		root := tx.Bucket([]byte(bucketComments))
+		b := root.Bucket([]byte(slug))
+		if b == nil {
+			return nil
+		}
+		c := b.Cursor()
+		var k, v []byte
+		if cursor == "" {
+			k, v = c.First()
+		} else {
+			// nextCursor 指向"下一页从这里继续"的那一条（还没有被上一页消费），
+			// 所以这里直接从它开始读，不能再 Next() 一次往后跳——不然每翻一页
+			// 就会把 cursor 指向的那条评论漏掉。
+			k, v = c.Seek([]byte(cursor))
+		}
+		for ; k != nil; k, v = c.Next() {
+			if len(comments) >= limit {
+				nextCursor = string(k)
+				return nil
+			}
+			var cm Comment
+			if err := json.Unmarshal(v, &cm); err == nil {
+				comments = append(comments, cm)
+			}
+		}
+		return nil
+	})
+	return comments, nextCursor, err
+}
+
+func appendComment(slug string, c Comment) error {
+	return blogDB.Update(func(tx *bolt.Tx) error {
+		root := tx.Bucket([]byte(bucketComments))
+		b, err := root.CreateBucketIfNotExists([]byte(slug))
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(c)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(newULID()), data)
+	})
+}
+
+// migrateLegacyComments 把 ./data/comments/<slug>.json 导进 bbolt，然后把原文件
+// 改名成 .bak。只在对应 slug 的 bucket 还是空的时候才导，避免重启时重复导入。
+func migrateLegacyComments() {
+	entries, err := os.ReadDir(commentDir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		slug := strings.TrimSuffix(e.Name(), ".json")
+		migrateLegacyCommentsFile(slug, filepath.Join(commentDir, e.Name()))
+	}
+}
+
+func migrateLegacyCommentsFile(slug, path string) {
+	if commentsBucketHasEntries(slug) {
+		return
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	var cs []Comment
+	if err := json.Unmarshal(data, &cs); err != nil {
+		log.Printf("迁移评论文件 %s 失败: %v", path, err)
+		return
+	}
+	for _, c := range cs {
+		if err := appendComment(slug, c); err != nil {
+			log.Printf("迁移 %s 的评论失败: %v", slug, err)
+		}
+	}
+	if err := os.Rename(path, path+".bak"); err != nil {
+		log.Printf("重命名 %s 失败: %v", path, err)
+	}
+}
+
+func commentsBucketHasEntries(slug string) bool {
+	has := false
+	_ = blogDB.View(func(tx *bolt.Tx) error {
+		root := tx.Bucket([]byte(bucketComments))
+		b := root.Bucket([]byte(slug))
+		if b == nil {
+			return nil
+		}
+		k, _ := b.Cursor().First()
+		has = k != nil
+		return nil
+	})
+	return has
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// withTestBlogStore 把包级的 blogDB 换成一个临时文件里的全新 bbolt 库，测试结束后
+// 还原，这样 loadComments/appendComment 不用碰到真实的 ./data/blog.db。
+func withTestBlogStore(t *testing.T) {
+	t.Helper()
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0o600, nil)
+	if err != nil {
+		t.Fatalf("bolt.Open() failed: %v", err)
+	}
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(bucketComments))
+		return err
+	}); err != nil {
+		t.Fatalf("creating comments bucket failed: %v", err)
+	}
+
+	old := blogDB
+	blogDB = db
+	t.Cleanup(func() {
+		db.Close()
+		blogDB = old
+	})
+}
+
+func TestLoadCommentsCursorPagination(t *testing.T) {
+	withTestBlogStore(t)
+
+	const slug = "hello-world"
+	for i := 0; i < 5; i++ {
+		c := Comment{Author: "a", Content: "comment", CreatedAt: time.Now()}
+		if err := appendComment(slug, c); err != nil {
+			t.Fatalf("appendComment() failed: %v", err)
+		}
+	}
+
+	page1, cursor1, err := loadComments(slug, "", 2)
+	if err != nil {
+		t.Fatalf("loadComments() page 1 failed: %v", err)
+	}
+	if len(page1) != 2 || cursor1 == "" {
+		t.Fatalf("loadComments() page 1 = %d comments, cursor=%q, want 2 comments and a cursor", len(page1), cursor1)
+	}
+
+	page2, cursor2, err := loadComments(slug, cursor1, 2)
+	if err != nil {
+		t.Fatalf("loadComments() page 2 failed: %v", err)
+	}
+	if len(page2) != 2 || cursor2 == "" {
+		t.Fatalf("loadComments() page 2 = %d comments, cursor=%q, want 2 comments and a cursor", len(page2), cursor2)
+	}
+
+	page3, cursor3, err := loadComments(slug, cursor2, 2)
+	if err != nil {
+		t.Fatalf("loadComments() page 3 failed: %v", err)
+	}
+	if len(page3) != 1 || cursor3 != "" {
+		t.Fatalf("loadComments() page 3 = %d comments, cursor=%q, want 1 comment and no cursor", len(page3), cursor3)
+	}
+}
+
+func TestLoadCommentsEmptySlug(t *testing.T) {
+	withTestBlogStore(t)
+
+	comments, cursor, err := loadComments("no-such-slug", "", 10)
+	if err != nil {
+		t.Fatalf("loadComments() failed: %v", err)
+	}
+	if len(comments) != 0 || cursor != "" {
+		t.Fatalf("loadComments() on empty slug = %d comments, cursor=%q, want none", len(comments), cursor)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"sync"
+	"time"
+)
+
+// 极简 ULID 实现：48 位毫秒时间戳 + 80 位随机数，编码成 Crockford base32，
+// 26 个字符，按字典序排列即按时间顺序排列，正好拿来当 bbolt 的 key 用。
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+var ulidMu sync.Mutex
+var lastULIDTime int64
+var lastULIDEntropy [10]byte
+
+// newULID 生成一个单调递增的 ULID：同一毫秒内多次调用时，在上一次的随机数上加一，
+// 保证同一毫秒内生成的 key 也不会乱序。
+func newULID() string {
+	ulidMu.Lock()
+	defer ulidMu.Unlock()
+
+	ms := time.Now().UnixMilli()
+	var entropy [10]byte
+	if ms == lastULIDTime {
+		entropy = lastULIDEntropy
+		incrementEntropy(&entropy)
+	} else {
+		if _, err := rand.Read(entropy[:]); err != nil {
+			// crypto/rand 几乎不会失败；退化成时间戳重复也不影响排序正确性
+		}
+	}
+	lastULIDTime = ms
+	lastULIDEntropy = entropy
+
+	var ts [6]byte
+	var buf64 [8]byte
+	binary.BigEndian.PutUint64(buf64[:], uint64(ms))
+	copy(ts[:], buf64[2:])
+
+	var full [16]byte
+	copy(full[:6], ts[:])
+	copy(full[6:], entropy[:])
+
+	return encodeCrockford(full)
+}
+
+func incrementEntropy(e *[10]byte) {
+	for i := len(e) - 1; i >= 0; i-- {
+		e[i]++
+		if e[i] != 0 {
+			return
+		}
+	}
+}
+
+func encodeCrockford(data [16]byte) string {
+	out := make([]byte, 26)
+	var acc uint64
+	bits := 0
+	idx := 25
+	for i := len(data) - 1; i >= 0; i-- {
+		acc |= uint64(data[i]) << bits
+		bits += 8
+		for bits >= 5 {
+			out[idx] = crockford[acc&0x1f]
+			idx--
+			acc >>= 5
+			bits -= 5
+		}
+	}
+	for idx >= 0 {
+		out[idx] = crockford[acc&0x1f]
+		idx--
+		acc >>= 5
+	}
+	return string(out)
+}
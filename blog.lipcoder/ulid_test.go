@@ -0,0 +1,37 @@
+package main
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestNewULIDMonotonicWithinSameMillisecond(t *testing.T) {
+	ids := make([]string, 50)
+	for i := range ids {
+		ids[i] = newULID()
+	}
+
+	sorted := append([]string(nil), ids...)
+	sort.Strings(sorted)
+	for i := range ids {
+		if ids[i] != sorted[i] {
+			t.Fatalf("newULID() output not lexically sorted: got %v, want %v", ids, sorted)
+		}
+	}
+}
+
+func TestNewULIDLength(t *testing.T) {
+	id := newULID()
+	if len(id) != 26 {
+		t.Fatalf("newULID() length = %d, want 26 (got %q)", len(id), id)
+	}
+}
+
+func TestIncrementEntropyCarries(t *testing.T) {
+	e := [10]byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0xff}
+	incrementEntropy(&e)
+	want := [10]byte{0, 0, 0, 0, 0, 0, 0, 0, 1, 0}
+	if e != want {
+		t.Fatalf("incrementEntropy carry = %v, want %v", e, want)
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const templatesDir = "templates"
+const debounceDelay = 300 * time.Millisecond
+
+// startWatcher 监听 markdown 目录和 templates 目录，文件一变就热加载，不用再
+// 重启进程才能看到改动。容器里 inotify 不一定好使，失败了就只剩 /-/reload。
+func startWatcher(markdownRoot string) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	if err := addRecursive(watcher, markdownRoot); err != nil {
+		return err
+	}
+	if err := watcher.Add(templatesDir); err != nil {
+		log.Printf("监听 %s 失败: %v", templatesDir, err)
+	}
+
+	debouncer := newDebouncer(debounceDelay, func(paths map[string]struct{}) {
+		handleWatchedChanges(watcher, markdownRoot, paths)
+	})
+
+	go func() {
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				debouncer.add(event.Name)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Printf("fsnotify 错误: %v", err)
+			}
+		}
+	}()
+
+	return nil
+}
+
+func addRecursive(watcher *fsnotify.Watcher, root string) error {
+	return filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return watcher.Add(path)
+		}
+		return nil
+	})
+}
+
+func handleWatchedChanges(watcher *fsnotify.Watcher, markdownRoot string, paths map[string]struct{}) {
+	for path := range paths {
+		switch {
+		case strings.HasPrefix(filepath.Clean(path), filepath.Clean(templatesDir)):
+			reloadTemplates()
+		case strings.HasSuffix(path, ".md") || strings.HasSuffix(path, ".markdown"):
+			reloadOnePath(markdownRoot, path)
+		default:
+			// 新建的目录：把它也加进监听，这样子目录里新加文件也能收到事件
+			if info, err := os.Stat(path); err == nil && info.IsDir() {
+				_ = watcher.Add(path)
+			}
+		}
+	}
+}
+
+// reloadOnePath 只重新解析改动的那一个 markdown 文件，并把搜索索引也增量更新
+// 到位（indexPost/deletePostFromIndex），不用把全站的 postsBySlug/allPosts
+// 或者整个搜索索引重建一遍——chunk0-7 加 indexPost 就是为了这个。如果文件已经
+// 被删掉/改名走了，就把对应的 slug 从 postsBySlug / allPosts 和索引里都摘掉。
+func reloadOnePath(root, path string) {
+	slug := makeSlug(path, root)
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		removePost(slug)
+		if err := deletePostFromIndex(slug); err != nil {
+			log.Printf("从搜索索引删除 %s 失败: %v", slug, err)
+		}
+		log.Printf("文章 %s 已删除，摘掉", slug)
+		return
+	}
+
+	post, err := loadOnePost(path, root)
+	if err != nil {
+		log.Printf("重新加载 %s 失败: %v", path, err)
+		return
+	}
+	upsertPost(post)
+	if err := indexPost(post); err != nil {
+		log.Printf("更新搜索索引 %s 失败: %v", slug, err)
+	}
+	log.Printf("文章 %s 已热加载", slug)
+}
+
+func upsertPost(post *Post) {
+	postsMu.Lock()
+	if postsBySlug == nil {
+		postsBySlug = make(map[string]*Post)
+	}
+	postsBySlug[post.Slug] = post
+	replaced := false
+	for i, p := range allPosts {
+		if p.Slug == post.Slug {
+			allPosts[i] = post
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		allPosts = append(allPosts, post)
+	}
+	sortPostsByDate(allPosts)
+	postsMu.Unlock()
+}
+
+func removePost(slug string) {
+	postsMu.Lock()
+	delete(postsBySlug, slug)
+	kept := allPosts[:0]
+	for _, p := range allPosts {
+		if p.Slug != slug {
+			kept = append(kept, p)
+		}
+	}
+	allPosts = kept
+	postsMu.Unlock()
+}
+
+func reloadTemplates() {
+	t, err := parseTemplates()
+	if err != nil {
+		log.Printf("重新解析模板失败: %v", err)
+		return
+	}
+	setTemplates(t)
+	log.Println("模板已热加载")
+}
+
+// handleReload 是 /-/reload：容器里没有 inotify 的时候用来手动强制全量重建。
+func handleReload(w http.ResponseWriter, r *http.Request) {
+	if !checkAdminBasicAuth(r) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="admin"`)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	markdownDir := os.Getenv(envMarkdownDir)
+	if markdownDir == "" {
+		markdownDir = defaultMarkdownDir
+	}
+
+	posts, bySlug, err := loadPosts(markdownDir)
+	if err != nil {
+		http.Error(w, "重新加载 markdown 失败: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	setPosts(posts, bySlug)
+
+	if t, err := parseTemplates(); err != nil {
+		http.Error(w, "重新解析模板失败: "+err.Error(), http.StatusInternalServerError)
+		return
+	} else {
+		setTemplates(t)
+	}
+
+	if err := buildSearchIndex(getAllPosts()); err != nil {
+		http.Error(w, "重建搜索索引失败: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Write([]byte("ok"))
+}
+
+// debouncer 把短时间内密集触发的一批文件事件合并成一次回调，按 300ms 静默期触发。
+type debouncer struct {
+	mu      sync.Mutex
+	delay   time.Duration
+	pending map[string]struct{}
+	timer   *time.Timer
+	fire    func(map[string]struct{})
+}
+
+func newDebouncer(delay time.Duration, fire func(map[string]struct{})) *debouncer {
+	return &debouncer{delay: delay, pending: make(map[string]struct{}), fire: fire}
+}
+
+func (d *debouncer) add(path string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.pending[path] = struct{}{}
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+	d.timer = time.AfterFunc(d.delay, d.flush)
+}
+
+func (d *debouncer) flush() {
+	d.mu.Lock()
+	batch := d.pending
+	d.pending = make(map[string]struct{})
+	d.mu.Unlock()
+	if len(batch) > 0 {
+		d.fire(batch)
+	}
+}
@@ -6,7 +6,6 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
-	"sync"
 	"time"
 )
 
@@ -33,68 +32,8 @@ type GuestbookEntry struct {
 	CreatedAt int64  `json:"created_at"`
 }
 
-var (
-	mu sync.Mutex
-)
-
-// 通用：把切片写入 json 文件（先写 tmp 再原子替换）
-func saveJSON(path string, v interface{}) error {
-	data, err := json.MarshalIndent(v, "", "  ")
-	if err != nil {
-		return err
-	}
-
-	tmp := path + ".tmp"
-	if err := os.WriteFile(tmp, data, 0644); err != nil {
-		return err
-	}
-	return os.Rename(tmp, path)
-}
-
-func loadFriends() ([]Friend, error) {
-	_, err := os.Stat(friendsPath)
-	if os.IsNotExist(err) {
-		return []Friend{}, nil
-	}
-	data, err := os.ReadFile(friendsPath)
-	if err != nil {
-		return []Friend{}, err
-	}
-	if len(data) == 0 {
-		return []Friend{}, nil
-	}
-	var list []Friend
-	if err := json.Unmarshal(data, &list); err != nil {
-		// 解析失败时，返回空列表，和原来 Python 容错逻辑类似
-		return []Friend{}, nil
-	}
-	return list, nil
-}
-
-func loadGuestbook() ([]GuestbookEntry, error) {
-	_, err := os.Stat(guestbookPath)
-	if os.IsNotExist(err) {
-		return []GuestbookEntry{}, nil
-	}
-	data, err := os.ReadFile(guestbookPath)
-	if err != nil {
-		return []GuestbookEntry{}, err
-	}
-	if len(data) == 0 {
-		return []GuestbookEntry{}, nil
-	}
-	var list []GuestbookEntry
-	if err := json.Unmarshal(data, &list); err != nil {
-		return []GuestbookEntry{}, nil
-	}
-	return list, nil
-}
-
 // /api/friends
 func friendsHandler(w http.ResponseWriter, r *http.Request) {
-	mu.Lock()
-	defer mu.Unlock()
-
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 
 	switch r.Method {
@@ -135,16 +74,30 @@ func friendsHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		list, _ := loadFriends()
+		offender := requestOffender(r)
+		if isFrozen(offender) {
+			w.WriteHeader(http.StatusForbidden)
+			_ = json.NewEncoder(w).Encode(map[string]string{
+				"error": "frozen",
+			})
+			return
+		}
+		if rejected, _ := checkSpam(offender, body.Name+" "+body.URL+" "+body.Desc); rejected {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{
+				"error": "rejected by sensitive-word filter",
+			})
+			return
+		}
+
 		entry := Friend{
 			Name:      body.Name,
 			URL:       body.URL,
 			Desc:      body.Desc,
 			CreatedAt: time.Now().Unix(),
 		}
-		list = append(list, entry)
 
-		if err := saveJSON(friendsPath, list); err != nil {
+		if err := appendFriend(entry); err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			_ = json.NewEncoder(w).Encode(map[string]string{
 				"error": "failed to save",
@@ -163,9 +116,6 @@ func friendsHandler(w http.ResponseWriter, r *http.Request) {
 
 // /api/guestbook
 func guestbookHandler(w http.ResponseWriter, r *http.Request) {
-	mu.Lock()
-	defer mu.Unlock()
-
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 
 	switch r.Method {
@@ -206,16 +156,30 @@ func guestbookHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		list, _ := loadGuestbook()
+		offender := requestOffender(r)
+		if isFrozen(offender) {
+			w.WriteHeader(http.StatusForbidden)
+			_ = json.NewEncoder(w).Encode(map[string]string{
+				"error": "frozen",
+			})
+			return
+		}
+		if rejected, _ := checkSpam(offender, body.Nickname+" "+body.Content); rejected {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{
+				"error": "rejected by sensitive-word filter",
+			})
+			return
+		}
+
 		entry := GuestbookEntry{
 			Nickname:  body.Nickname,
 			Contact:   body.Contact,
 			Content:   body.Content,
 			CreatedAt: time.Now().Unix(),
 		}
-		list = append(list, entry)
 
-		if err := saveJSON(guestbookPath, list); err != nil {
+		if err := appendGuestbookEntry(entry); err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			_ = json.NewEncoder(w).Encode(map[string]string{
 				"error": "failed to save",
@@ -243,11 +207,19 @@ func main() {
 		log.Fatalf("failed to create data dir: %v", err)
 	}
 
+	if err := openStore(); err != nil {
+		log.Fatalf("failed to open bbolt store: %v", err)
+	}
+	defer db.Close()
+	migrateLegacyJSON()
+	loadSpamWordLists()
+
 	mux := http.NewServeMux()
 
 	// API 路由（保持和原 Flask 一致）
 	mux.HandleFunc("/api/friends", friendsHandler)
 	mux.HandleFunc("/api/guestbook", guestbookHandler)
+	mux.HandleFunc("/admin/frozen", handleAdminFrozen)
 
 	// 静态文件：public 目录
 	fs := http.FileServer(http.Dir(publicDir))
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// normalizeForSpamCheck 把输入整理成一种规范形式再去跟词表比对，免得
+// "f​u‌c‍k" 这种插了零宽字符、或者用全角字符拼出来的敏感词绕过去。
+func normalizeForSpamCheck(s string) string {
+	s = norm.NFKC.String(s)
+	s = strings.ToLower(s)
+
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range s {
+		if isZeroWidth(r) || unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func isZeroWidth(r rune) bool {
+	switch r {
+	case '\u200b', '\u200c', '\u200d', '\ufeff':
+		return true
+	default:
+		return false
+	}
+}
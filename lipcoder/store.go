@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// 把 friends.json / guestbook.json 两个只会越长越大的 JSON 文件换成一个
+// 内嵌的 bbolt 库，每条记录一个 ULID key，读写都走 bbolt 自带的事务，
+// 不再需要一把全局锁挡住所有请求。
+const (
+	dbPath           = "data/lipcoder.db"
+	bucketFriends    = "friends"
+	bucketGuestbook  = "guestbook"
+)
+
+var db *bolt.DB
+
+func openStore() error {
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return err
+	}
+	var err error
+	db, err = bolt.Open(dbPath, 0600, nil)
+	if err != nil {
+		return err
+	}
+	return db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists([]byte(bucketFriends)); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists([]byte(bucketGuestbook))
+		return err
+	})
+}
+
+func loadFriends() ([]Friend, error) {
+	var list []Friend
+	err := db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucketFriends))
+		return b.ForEach(func(k, v []byte) error {
+			var f Friend
+			if err := json.Unmarshal(v, &f); err != nil {
+				return nil // 坏记录跳过，别把整个列表搞挂
+			}
+			list = append(list, f)
+			return nil
+		})
+	})
+	if list == nil {
+		list = []Friend{}
+	}
+	return list, err
+}
+
+func appendFriend(f Friend) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucketFriends))
+		data, err := json.Marshal(f)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(newULID()), data)
+	})
+}
+
+func loadGuestbook() ([]GuestbookEntry, error) {
+	var list []GuestbookEntry
+	err := db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucketGuestbook))
+		return b.ForEach(func(k, v []byte) error {
+			var e GuestbookEntry
+			if err := json.Unmarshal(v, &e); err != nil {
+				return nil
+			}
+			list = append(list, e)
+			return nil
+		})
+	})
+	if list == nil {
+		list = []GuestbookEntry{}
+	}
+	return list, err
+}
+
+func appendGuestbookEntry(e GuestbookEntry) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucketGuestbook))
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(newULID()), data)
+	})
+}
+
+// migrateLegacyJSON 把老的 friends.json / guestbook.json 导入 bbolt，然后把原文件
+// 改名成 .bak，留个底但不再被读取。只在对应 bucket 还是空的时候跑，避免重复导入。
+func migrateLegacyJSON() {
+	migrateFriendsJSON()
+	migrateGuestbookJSON()
+}
+
+func migrateFriendsJSON() {
+	if bucketHasEntries(bucketFriends) {
+		return
+	}
+	data, err := os.ReadFile(friendsPath)
+	if err != nil {
+		return
+	}
+	var list []Friend
+	if err := json.Unmarshal(data, &list); err != nil {
+		log.Printf("迁移 friends.json 失败: %v", err)
+		return
+	}
+	for _, f := range list {
+		if err := appendFriend(f); err != nil {
+			log.Printf("迁移 friend 记录失败: %v", err)
+		}
+	}
+	if err := os.Rename(friendsPath, friendsPath+".bak"); err != nil {
+		log.Printf("重命名 friends.json 失败: %v", err)
+	}
+}
+
+func migrateGuestbookJSON() {
+	if bucketHasEntries(bucketGuestbook) {
+		return
+	}
+	data, err := os.ReadFile(guestbookPath)
+	if err != nil {
+		return
+	}
+	var list []GuestbookEntry
+	if err := json.Unmarshal(data, &list); err != nil {
+		log.Printf("迁移 guestbook.json 失败: %v", err)
+		return
+	}
+	for _, e := range list {
+		if err := appendGuestbookEntry(e); err != nil {
+			log.Printf("迁移 guestbook 记录失败: %v", err)
+		}
+	}
+	if err := os.Rename(guestbookPath, guestbookPath+".bak"); err != nil {
+		log.Printf("重命名 guestbook.json 失败: %v", err)
+	}
+}
+
+func bucketHasEntries(name string) bool {
+	has := false
+	_ = db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(name))
+		k, _ := b.Cursor().First()
+		has = k != nil
+		return nil
+	})
+	return has
+}